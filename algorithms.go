@@ -0,0 +1,57 @@
+package gossh
+
+// AlgorithmsSecure returns the zero-value algorithm lists, telling x/crypto/ssh to use its own
+// defaults.
+func AlgorithmsSecure() (keyExchanges, ciphers, macs, hostKeyAlgorithms []string) {
+	return nil, nil, nil, nil
+}
+
+// AlgorithmsCompat adds older KEX, cipher, MAC and host key algorithms on top of the secure
+// defaults, for legacy devices that don't speak anything modern.
+func AlgorithmsCompat() (keyExchanges, ciphers, macs, hostKeyAlgorithms []string) {
+	keyExchanges = []string{
+		"curve25519-sha256", "curve25519-sha256@libssh.org",
+		"ecdh-sha2-nistp256", "ecdh-sha2-nistp384", "ecdh-sha2-nistp521",
+		"diffie-hellman-group14-sha256", "diffie-hellman-group14-sha1",
+		"diffie-hellman-group1-sha1",
+	}
+	ciphers = []string{
+		"aes128-gcm@openssh.com", "aes256-gcm@openssh.com",
+		"chacha20-poly1305@openssh.com",
+		"aes128-ctr", "aes192-ctr", "aes256-ctr",
+		"aes128-cbc", "3des-cbc",
+	}
+	macs = []string{
+		"hmac-sha2-256-etm@openssh.com", "hmac-sha2-512-etm@openssh.com",
+		"hmac-sha2-256", "hmac-sha2-512",
+		"hmac-sha1",
+	}
+	hostKeyAlgorithms = []string{
+		"ssh-ed25519",
+		"ecdsa-sha2-nistp256", "ecdsa-sha2-nistp384", "ecdsa-sha2-nistp521",
+		"rsa-sha2-512", "rsa-sha2-256",
+		"ssh-rsa",
+	}
+	return
+}
+
+// AlgorithmsFIPS returns the subset of KEX, cipher, MAC and host key algorithms approved under
+// FIPS 140-2/140-3.
+func AlgorithmsFIPS() (keyExchanges, ciphers, macs, hostKeyAlgorithms []string) {
+	keyExchanges = []string{
+		"ecdh-sha2-nistp256", "ecdh-sha2-nistp384", "ecdh-sha2-nistp521",
+		"diffie-hellman-group14-sha256",
+	}
+	ciphers = []string{
+		"aes128-gcm@openssh.com", "aes256-gcm@openssh.com",
+		"aes128-ctr", "aes192-ctr", "aes256-ctr",
+	}
+	macs = []string{
+		"hmac-sha2-256", "hmac-sha2-512",
+	}
+	hostKeyAlgorithms = []string{
+		"ecdsa-sha2-nistp256", "ecdsa-sha2-nistp384", "ecdsa-sha2-nistp521",
+		"rsa-sha2-512", "rsa-sha2-256",
+	}
+	return
+}
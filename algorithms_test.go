@@ -0,0 +1,22 @@
+package gossh
+
+import "testing"
+
+func TestAlgorithmsSecure(t *testing.T) {
+	kex, ciphers, macs, hostKeyAlgos := AlgorithmsSecure()
+	if kex != nil || ciphers != nil || macs != nil || hostKeyAlgos != nil {
+		t.Errorf("AlgorithmsSecure() = %v, %v, %v, %v, want all nil", kex, ciphers, macs, hostKeyAlgos)
+	}
+}
+
+func TestAlgorithmsCompatAndFIPS(t *testing.T) {
+	for name, fn := range map[string]func() ([]string, []string, []string, []string){
+		"AlgorithmsCompat": AlgorithmsCompat,
+		"AlgorithmsFIPS":   AlgorithmsFIPS,
+	} {
+		kex, ciphers, macs, hostKeyAlgos := fn()
+		if len(kex) == 0 || len(ciphers) == 0 || len(macs) == 0 || len(hostKeyAlgos) == 0 {
+			t.Errorf("%s() returned an empty list: kex=%v ciphers=%v macs=%v hostKeyAlgos=%v", name, kex, ciphers, macs, hostKeyAlgos)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/kkrs/gossh"
+)
+
+// runForward implements the "forward" verb: gossh forward -R remote:port:local:port sets up
+// reverse port forwarding through the selected host, gossh forward -L local:port:remote:port sets
+// up local port forwarding; both may be given at once. The tunnels stay open until interrupted.
+func runForward(args []string) {
+	fs := flag.NewFlagSet("gossh forward", flag.ExitOnError)
+	c := bindCommonFlags(fs)
+	var remoteSpec, localSpec string
+	fs.StringVar(&remoteSpec, "R", "", "reverse forward: remote_host:remote_port:local_host:local_port")
+	fs.StringVar(&localSpec, "L", "", "local forward: local_host:local_port:remote_host:remote_port")
+	fs.Parse(args)
+
+	if remoteSpec == "" && localSpec == "" {
+		usage("gossh forward [flags] -R remote_host:remote_port:local_host:local_port | -L local_host:local_port:remote_host:remote_port")
+	}
+
+	hosts, err := targets(c.rangeExpr, c.hostsFile)
+	if err != nil {
+		fatal(err)
+	}
+	if len(hosts) != 1 {
+		fatal("gossh forward requires exactly one host")
+	}
+
+	cfg, err := c.config()
+	if err != nil {
+		fatal(err)
+	}
+	cfg.Logger = gossh.GetLogger("main", 2)
+
+	client, err := gossh.Dial(hosts[0], cfg)
+	if err != nil {
+		fatal(err)
+	}
+	defer client.Close()
+
+	var closers []io.Closer
+	if remoteSpec != "" {
+		remoteAddr, localAddr, err := splitForwardSpec(remoteSpec)
+		if err != nil {
+			fatal(err)
+		}
+		c, err := client.ForwardRemote(remoteAddr, func(ctx context.Context) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "tcp", localAddr)
+		})
+		if err != nil {
+			fatal(err)
+		}
+		closers = append(closers, c)
+		printf("forward: ", "remote %s -> local %s", remoteAddr, localAddr)
+	}
+	if localSpec != "" {
+		localAddr, remoteAddr, err := splitForwardSpec(localSpec)
+		if err != nil {
+			fatal(err)
+		}
+		c, err := client.ForwardLocal(localAddr, remoteAddr)
+		if err != nil {
+			fatal(err)
+		}
+		closers = append(closers, c)
+		printf("forward: ", "local %s -> remote %s", localAddr, remoteAddr)
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+
+	for _, c := range closers {
+		c.Close()
+	}
+}
+
+// splitForwardSpec splits an ssh(1)-style "host:port:host:port" forward spec into its two
+// host:port halves.
+func splitForwardSpec(spec string) (first, second string, err error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) != 4 {
+		return "", "", fmt.Errorf("invalid forward spec %q, want host:port:host:port", spec)
+	}
+	return parts[0] + ":" + parts[1], parts[2] + ":" + parts[3], nil
+}
@@ -1,19 +1,81 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/kkrs/gossh"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
 )
 
+// options collects repeatable "-o name=value" flags in ssh_config's own style. The last value set
+// for a given name wins, matching ssh(1).
+type options map[string][]string
+
+func (o *options) String() string {
+	return fmt.Sprint(map[string][]string(*o))
+}
+
+func (o *options) Set(s string) error {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid -o option %q, want name=value", s)
+	}
+	if *o == nil {
+		*o = make(options)
+	}
+	(*o)[parts[0]] = append((*o)[parts[0]], parts[1])
+	return nil
+}
+
+func (o options) get(name string) (string, bool) {
+	v, ok := o[name]
+	if !ok || len(v) == 0 {
+		return "", false
+	}
+	return v[len(v)-1], true
+}
+
+// splitCSV splits an ssh_config-style comma separated option value, e.g. the right-hand side of
+// "-o Ciphers=aes128-ctr,aes256-ctr". An empty value yields a nil slice so it leaves the
+// corresponding gossh.Config field (and thus the x/crypto/ssh default) untouched.
+func splitCSV(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+func hostKeyPolicy(o options, skipHostKeyCheck bool) (gossh.HostKeyPolicy, error) {
+	if skipHostKeyCheck {
+		return gossh.IgnoreHostKeys, nil
+	}
+	v, ok := o.get("StrictHostKeyChecking")
+	if !ok {
+		return gossh.StrictHostKeyChecking, nil
+	}
+	switch v {
+	case "yes":
+		return gossh.StrictHostKeyChecking, nil
+	case "accept-new":
+		return gossh.AcceptNewHostKeys, nil
+	case "no":
+		return gossh.IgnoreHostKeys, nil
+	default:
+		return gossh.StrictHostKeyChecking, fmt.Errorf("invalid StrictHostKeyChecking value %q, want yes, no or accept-new", v)
+	}
+}
+
 func print(prefix string, v ...interface{}) {
 	msg := fmt.Sprint(v...)
 	if msg[len(msg)-1] != '\n' {
@@ -59,7 +121,7 @@ func exists(path string) bool {
 
 func identityFiles(methods []ssh.AuthMethod, files ...string) []ssh.AuthMethod {
 	for _, k := range files {
-		m, err := gossh.IdentityFile(k)
+		m, err := gossh.IdentityFilePrompt(k, promptPassphrase)
 		if err != nil {
 			warn(err)
 			continue
@@ -69,6 +131,39 @@ func identityFiles(methods []ssh.AuthMethod, files ...string) []ssh.AuthMethod {
 	return methods
 }
 
+// promptPassphrase asks for an identity file's passphrase on /dev/tty, falling back to
+// $SSH_ASKPASS when stdin isn't a terminal (e.g. running under cron or from another program).
+func promptPassphrase(fingerprint string) ([]byte, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		if askpass := os.Getenv("SSH_ASKPASS"); askpass != "" {
+			return askpassPassphrase(askpass, fingerprint)
+		}
+	}
+	return ttyPassphrase(fingerprint)
+}
+
+func ttyPassphrase(fingerprint string) ([]byte, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer tty.Close()
+
+	fmt.Fprintf(tty, "Enter passphrase for key (%s): ", fingerprint)
+	passphrase, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(tty)
+	return passphrase, err
+}
+
+func askpassPassphrase(askpass, fingerprint string) ([]byte, error) {
+	cmd := exec.Command(askpass, fmt.Sprintf("Enter passphrase for key (%s):", fingerprint))
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("SSH_ASKPASS(%s): %s", askpass, err)
+	}
+	return bytes.TrimRight(out, "\n"), nil
+}
+
 func agentKeys(methods []ssh.AuthMethod, authSock string) []ssh.AuthMethod {
 	if exists(authSock) {
 		agentMethod, err := gossh.AgentKeys(authSock)
@@ -90,7 +185,12 @@ var (
 
 func printAndCollateStatus(host string, status error) {
 	gossh.PrintStatus(host, status)
+	collateStatus(host, status)
+}
 
+// collateStatus tracks host success/failure for the final summary without printing anything,
+// for output formats (e.g. JSON) that already carry a result's status themselves.
+func collateStatus(host string, status error) {
 	mu.Lock()
 	defer mu.Unlock()
 	if status != nil {
@@ -125,71 +225,169 @@ func targets(rangeExpr, hostsFile string) ([]string, error) {
 	return nil, errors.New("missing argument, one of range, hostsFile required")
 }
 
-func main() {
-	var (
-		identities     string
-		useAgent       bool
-		login          string
-		port           string
-		rangeExpr      string
-		hostsFile      string
-		maxFlight      int
-		connTimeout    float64
-		sessionTimeout float64
-		displayVersion bool
-	)
+// commonFlags are the host-selection and connection flags shared by the default "run a command"
+// mode and the push/pull transfer verbs.
+type commonFlags struct {
+	identities       string
+	useAgent         bool
+	login            string
+	port             string
+	rangeExpr        string
+	hostsFile        string
+	maxFlight        int
+	connTimeout      float64
+	sessionTimeout   float64
+	idleTimeout      float64
+	skipHostKeyCheck bool
+	opts             options
+	proxyJump        string
+}
+
+func bindCommonFlags(fs *flag.FlagSet) *commonFlags {
+	c := &commonFlags{}
 	authSock := os.Getenv("SSH_AUTH_SOCK")
 
-	flag.StringVar(&identities, "i", "", "identity files to use")
+	fs.StringVar(&c.identities, "i", "", "identity files to use")
 	useAgentMsg := fmt.Sprintf("use agent defined at $SSH_AUTH_SOCK(%s)", authSock)
-	flag.BoolVar(&useAgent, "G", true, useAgentMsg)
-	flag.StringVar(&login, "l", os.Getenv("LOGNAME"), "login name")
-	flag.StringVar(&port, "p", "22", "port to connect on")
-	flag.StringVar(&rangeExpr, "r", "", "range to run command on")
-	flag.StringVar(&hostsFile, "H", "", "file containing hosts to run the command on")
-	flag.IntVar(&maxFlight, "m", 100, "maximum number of connections in flight")
-	flag.Float64Var(&connTimeout, "c", 10.0, "connection timeout in seconds, 0 for none")
-	flag.Float64Var(&sessionTimeout, "t", 0, "session timeout in seconds, 0 for none")
-	flag.BoolVar(&displayVersion, "version", false, "version")
-	flag.Parse()
-
-	cmd := strings.Join(flag.Args(), " ")
+	fs.BoolVar(&c.useAgent, "G", true, useAgentMsg)
+	fs.StringVar(&c.login, "l", os.Getenv("LOGNAME"), "login name")
+	fs.StringVar(&c.port, "p", "22", "port to connect on")
+	fs.StringVar(&c.rangeExpr, "r", "", "range to run command on")
+	fs.StringVar(&c.hostsFile, "H", "", "file containing hosts to run the command on")
+	fs.IntVar(&c.maxFlight, "m", 100, "maximum number of connections in flight")
+	fs.Float64Var(&c.connTimeout, "c", 10.0, "connection timeout in seconds, 0 for none")
+	fs.Float64Var(&c.sessionTimeout, "t", 0, "session timeout in seconds, 0 for none")
+	fs.Float64Var(&c.idleTimeout, "I", 0, "idle timeout in seconds, 0 for none; aborts the session if no output is seen for this long")
+	fs.BoolVar(&c.skipHostKeyCheck, "k", false, "skip host key verification (INSECURE)")
+	fs.Var(&c.opts, "o", "ssh_config-style option, may be repeated (e.g. -o StrictHostKeyChecking=accept-new, -o KexAlgorithms=…, -o Ciphers=…, -o MACs=…, -o OutputFormat=json)")
+	fs.StringVar(&c.proxyJump, "J", "", "bastion chain to connect through, e.g. user@bastion1:22,user@bastion2:2222")
+	return c
+}
+
+// config builds the gossh.Config shared by every verb from the parsed flags. Callers still need
+// to set StdoutHandler/StderrHandler/StatusHandler/Logger for what they're about to run.
+func (c *commonFlags) config() (*gossh.Config, error) {
+	var methods []ssh.AuthMethod
+	if c.identities != "" {
+		methods = identityFiles(methods, c.identities)
+	}
+	if c.useAgent {
+		methods = agentKeys(methods, os.Getenv("SSH_AUTH_SOCK"))
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("no authentication methods remain")
+	}
+
+	policy, err := hostKeyPolicy(c.opts, c.skipHostKeyCheck)
+	if err != nil {
+		return nil, err
+	}
+	hostKeyCallback, err := gossh.PolicyCallback(policy, gossh.DefaultKnownHostsPath())
+	if err != nil {
+		return nil, err
+	}
+
+	kexAlgos, _ := c.opts.get("KexAlgorithms")
+	ciphers, _ := c.opts.get("Ciphers")
+	macs, _ := c.opts.get("MACs")
+
+	return &gossh.Config{
+		Login:           c.login,
+		AuthMethods:     methods,
+		ConnectTimeout:  time.Millisecond * time.Duration(c.connTimeout*1000),
+		SessionTimeout:  time.Millisecond * time.Duration(c.sessionTimeout*1000),
+		IdleTimeout:     time.Millisecond * time.Duration(c.idleTimeout*1000),
+		HostKeyCallback: hostKeyCallback,
+		KeyExchanges:    splitCSV(kexAlgos),
+		Ciphers:         splitCSV(ciphers),
+		MACs:            splitCSV(macs),
+		ProxyJump:       splitCSV(c.proxyJump),
+	}, nil
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "push", "pull":
+			runTransfer(os.Args[1], os.Args[2:])
+			return
+		case "forward":
+			runForward(os.Args[2:])
+			return
+		}
+	}
+	runExec(os.Args[1:])
+}
+
+func runExec(args []string) {
+	fs := flag.NewFlagSet("gossh", flag.ExitOnError)
+	c := bindCommonFlags(fs)
+	var displayVersion bool
+	fs.BoolVar(&displayVersion, "version", false, "version")
+	// -t is already taken by -t <session timeout>, so the pty switch ssh spells -t lives here
+	// under its long name instead.
+	var ptyMode bool
+	fs.BoolVar(&ptyMode, "pty", false, "force pty allocation for an interactive session (mirrors ssh -t); only valid with a single host")
+	fs.Parse(args)
+
+	cmd := strings.Join(fs.Args(), " ")
 
 	if displayVersion {
 		fmt.Printf("%s %s\n", os.Args[0], Version)
 		os.Exit(0)
 	}
 
-	hosts, err := targets(rangeExpr, hostsFile)
+	hosts, err := targets(c.rangeExpr, c.hostsFile)
 	if err != nil {
 		fatal(err)
 	}
 
-	var methods []ssh.AuthMethod
-	if identities != "" {
-		methods = identityFiles(methods, identities)
+	if ptyMode {
+		if len(hosts) != 1 {
+			fatal("-pty does not support fanning out to multiple hosts")
+		}
+		cfg, err := c.config()
+		if err != nil {
+			fatal(err)
+		}
+		cfg.Logger = gossh.GetLogger("main", 2)
+		if err := runPTYSession(hosts[0], cmd, cfg); err != nil {
+			fatal(err)
+		}
+		return
 	}
 
-	if useAgent {
-		methods = agentKeys(methods, authSock)
+	cfg, err := c.config()
+	if err != nil {
+		fatal(err)
 	}
+	cfg.Logger = gossh.GetLogger("main", 2)
 
-	if len(methods) == 0 {
-		fatal("no authentication methods remain")
+	formatOpt, _ := c.opts.get("OutputFormat")
+	outputFormat, err := gossh.ParseOutputFormat(formatOpt)
+	if err != nil {
+		fatal(err)
 	}
+	cfg.OutputFormat = outputFormat
 
-	cfg := &gossh.Config{
-		login,
-		methods,
-		time.Millisecond * time.Duration(connTimeout*1000),
-		time.Millisecond * time.Duration(sessionTimeout*1000),
-		gossh.PrintStdout,
-		gossh.PrintStderr,
-		printAndCollateStatus,
-		gossh.GetLogger("main", 2),
+	switch cfg.OutputFormat {
+	case gossh.OutputJSON:
+		for hr := range gossh.RunOnContext(context.Background(), hosts, cmd, c.maxFlight, cfg) {
+			gossh.PrintResultJSON(hr)
+			collateStatus(hr.Host, hr.Err)
+		}
+	case gossh.OutputNDJSONStream:
+		cfg.StdoutHandler = gossh.NDJSONStdout
+		cfg.StderrHandler = gossh.NDJSONStderr
+		cfg.StatusHandler = printAndCollateStatus
+		gossh.RunOn(hosts, cmd, c.maxFlight, cfg)
+	default:
+		cfg.StdoutHandler = gossh.PrintStdout
+		cfg.StderrHandler = gossh.PrintStderr
+		cfg.StatusHandler = printAndCollateStatus
+		gossh.RunOn(hosts, cmd, c.maxFlight, cfg)
 	}
 
-	gossh.RunOn(hosts, cmd, maxFlight, cfg)
 	fmt.Println()
 	fmt.Printf("succeeded: %s\n", compress(succeeded))
 	fmt.Printf("failed: %s\n", compress(failed))
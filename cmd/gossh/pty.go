@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/kkrs/gossh"
+	"golang.org/x/term"
+)
+
+// runPTYSession dials host and runs cmd interactively on an allocated pty, mirroring `ssh -t`: it
+// puts the local terminal in raw mode for the duration (restoring it on exit) and forwards
+// SIGWINCH so remote programs see local terminal resizes.
+func runPTYSession(host, cmd string, cfg *gossh.Config) error {
+	client, err := gossh.Dial(host, cfg)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	opts := gossh.PTYOptions{
+		Term:   os.Getenv("TERM"),
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+
+	stdinFd := int(os.Stdin.Fd())
+	if !term.IsTerminal(stdinFd) {
+		return client.RunPTY(cmd, opts)
+	}
+
+	if width, height, err := term.GetSize(stdinFd); err == nil {
+		opts.Width, opts.Height = width, height
+	}
+
+	state, err := term.MakeRaw(stdinFd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(stdinFd, state)
+
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	defer signal.Stop(winch)
+
+	sizes := make(chan [2]int, 1)
+	opts.WindowChange = sizes
+	go func() {
+		for range winch {
+			if w, h, err := term.GetSize(stdinFd); err == nil {
+				sizes <- [2]int{w, h}
+			}
+		}
+	}()
+
+	return client.RunPTY(cmd, opts)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/kkrs/gossh"
+)
+
+// runTransfer implements the "push" and "pull" verbs: gossh push <src> <dst> copies the local
+// file src to dst on every selected host, gossh pull <src> <dst> copies the remote file src from
+// every selected host to dst locally. It reuses the same host-selection, auth and connection
+// flags as the default command-execution mode.
+func runTransfer(verb string, args []string) {
+	fs := flag.NewFlagSet("gossh "+verb, flag.ExitOnError)
+	c := bindCommonFlags(fs)
+	fs.Parse(args)
+
+	rest := fs.Args()
+	if len(rest) != 2 {
+		usage("gossh %s [flags] <src> <dst>", verb)
+	}
+	src, dst := rest[0], rest[1]
+
+	hosts, err := targets(c.rangeExpr, c.hostsFile)
+	if err != nil {
+		fatal(err)
+	}
+
+	cfg, err := c.config()
+	if err != nil {
+		fatal(err)
+	}
+	cfg.StdoutHandler = gossh.PrintStdout
+	cfg.StderrHandler = gossh.PrintStderr
+	cfg.StatusHandler = printAndCollateStatus
+	cfg.Logger = gossh.GetLogger("main", 2)
+
+	multi := len(hosts) > 1
+
+	if c.maxFlight < 1 {
+		c.maxFlight = 1
+	}
+	workers := new(sync.WaitGroup)
+	sem := make(chan struct{}, c.maxFlight)
+	for _, host := range hosts {
+		sem <- struct{}{}
+		workers.Add(1)
+		go func(host string) {
+			defer func() {
+				workers.Done()
+				<-sem
+			}()
+			transferOne(verb, host, src, dst, multi, cfg)
+		}(host)
+	}
+	workers.Wait()
+
+	fmt.Println()
+	fmt.Printf("succeeded: %s\n", compress(succeeded))
+	fmt.Printf("failed: %s\n", compress(failed))
+}
+
+// transferOne runs one push or pull against host, reporting progress and status through cfg's
+// handlers exactly like the command-execution path does.
+func transferOne(verb, host, src, dst string, multi bool, cfg *gossh.Config) {
+	client, err := gossh.Dial(host, cfg)
+	if err != nil {
+		return // gossh.Dial already reported err via cfg.StatusHandler
+	}
+	defer client.Close()
+
+	xfer, err := client.Transfer()
+	if err != nil {
+		cfg.StatusHandler(host, err)
+		return
+	}
+	defer xfer.Close()
+
+	switch verb {
+	case "push":
+		err = xfer.Put(src, dst, 0644)
+	case "pull":
+		dst = localDest(dst, host, multi)
+		err = xfer.Get(src, dst)
+	}
+
+	if err == nil {
+		cfg.StdoutHandler(host, strings.NewReader(fmt.Sprintf("%s %s -> %s\n", verb, src, dst)))
+	}
+	cfg.StatusHandler(host, err)
+}
+
+// localDest disambiguates the local destination of a pull across multiple hosts by suffixing it
+// with the hostname, the same way pscp-style tools do; a single host keeps dst as given.
+func localDest(dst, host string, multi bool) string {
+	if !multi {
+		return dst
+	}
+	return dst + "." + host
+}
@@ -0,0 +1,82 @@
+package gossh
+
+import (
+	"context"
+	"io"
+	"net"
+)
+
+// ForwardRemote implements reverse port forwarding (ssh -R): it asks the remote server to listen
+// on remoteAddr, and for every connection accepted there dials a fresh local connection via
+// dialLocal and pipes the two together. Closing the returned io.Closer stops the listener.
+func (cl *Client) ForwardRemote(remoteAddr string, dialLocal func(context.Context) (net.Conn, error)) (io.Closer, error) {
+	listener, err := cl.client.Listen("tcp", remoteAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			remoteConn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				localConn, err := dialLocal(context.Background())
+				if err != nil {
+					remoteConn.Close()
+					return
+				}
+				pipeConns(remoteConn, localConn)
+			}()
+		}
+	}()
+
+	return listener, nil
+}
+
+// ForwardLocal implements local port forwarding (ssh -L): it listens on localAddr, and for every
+// connection accepted there dials remoteAddr through cl's connection and pipes the two together.
+func (cl *Client) ForwardLocal(localAddr, remoteAddr string) (io.Closer, error) {
+	listener, err := net.Listen("tcp", localAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			localConn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				remoteConn, err := cl.client.Dial("tcp", remoteAddr)
+				if err != nil {
+					localConn.Close()
+					return
+				}
+				pipeConns(localConn, remoteConn)
+			}()
+		}
+	}()
+
+	return listener, nil
+}
+
+// pipeConns copies between a and b in both directions until either side is done, then closes
+// both.
+func pipeConns(a, b net.Conn) {
+	defer a.Close()
+	defer b.Close()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(a, b)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(b, a)
+		done <- struct{}{}
+	}()
+	<-done
+}
@@ -0,0 +1,41 @@
+package gossh
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestPipeConns(t *testing.T) {
+	a1, a2 := net.Pipe()
+	b1, b2 := net.Pipe()
+
+	done := make(chan struct{})
+	go func() {
+		pipeConns(a1, b1)
+		close(done)
+	}()
+
+	if _, err := a2.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(b2, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "ping" {
+		t.Fatalf("got %q, want %q", buf, "ping")
+	}
+
+	a2.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("pipeConns did not return after one side closed")
+	}
+
+	if _, err := b2.Write([]byte("x")); err == nil {
+		t.Fatal("pipeConns did not close the other side on exit")
+	}
+}
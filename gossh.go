@@ -2,6 +2,8 @@ package gossh
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -59,9 +61,13 @@ func PrintStatus(endpoint string, status error) {
 	}
 }
 
-// IdentityFile converts ssh key file to ssh.AuthMethod. Encrypted IdentityFiles are not handled for
-// now.
+// IdentityFile converts ssh key file to ssh.AuthMethod. If file is passphrase protected, use
+// IdentityFileWithPassphrase or IdentityFilePrompt instead.
 func IdentityFile(file string) (ssh.AuthMethod, error) {
+	if signer, ok := cachedSigner(file); ok {
+		return ssh.PublicKeys(signer), nil
+	}
+
 	pem, err := ioutil.ReadFile(file)
 	if err != nil {
 		return nil, fmt.Errorf("IdentityFile: %s", err)
@@ -69,9 +75,13 @@ func IdentityFile(file string) (ssh.AuthMethod, error) {
 
 	key, err := ssh.ParsePrivateKey(pem)
 	if err != nil {
+		if _, missing := err.(*ssh.PassphraseMissingError); missing {
+			return nil, fmt.Errorf("IdentityFile: %s is encrypted, use IdentityFileWithPassphrase or IdentityFilePrompt", file)
+		}
 		return nil, fmt.Errorf("IdentityFile: %s", err)
 	}
 
+	cacheSigner(file, key)
 	return ssh.PublicKeys(key), nil
 }
 
@@ -93,10 +103,50 @@ type Config struct {
 	AuthMethods    []ssh.AuthMethod
 	ConnectTimeout time.Duration
 	SessionTimeout time.Duration
-	StdoutHandler  OutputHandler
-	StderrHandler  OutputHandler
-	StatusHandler  StatusHandler
-	Logger         *Logger
+	// IdleTimeout, if non-zero, aborts the session if no stdout/stderr bytes are seen for this
+	// long, independent of (and bounded by) the wall-clock SessionTimeout.
+	IdleTimeout     time.Duration
+	HostKeyCallback ssh.HostKeyCallback
+
+	// KeyExchanges, Ciphers and MACs restrict or reorder the algorithms negotiated for key
+	// exchange, encryption and message authentication; a nil slice leaves the x/crypto/ssh
+	// default in place. HostKeyAlgorithms does the same for server host key verification. See
+	// AlgorithmsSecure, AlgorithmsCompat and AlgorithmsFIPS for ready-made presets.
+	KeyExchanges      []string
+	Ciphers           []string
+	MACs              []string
+	HostKeyAlgorithms []string
+
+	// ProxyJump chains the connection through one or more bastion hosts before reaching the
+	// target, each given as "user@host:port" (port defaults to 22). Open bastion connections are
+	// cached per unique chain prefix across a RunOn fan-out, so many destinations behind the same
+	// jumphost(s) share one connection to it instead of opening one each.
+	ProxyJump []string
+
+	// OutputFormat records which rendering a caller picked for this Config's results; RunOn and
+	// friends don't read it themselves (they always drive StdoutHandler/StderrHandler/
+	// StatusHandler), but callers building those handlers — like the CLI — use it to pick
+	// PrintStdout/PrintStderr, NDJSONStdout/NDJSONStderr, or PrintResultJSON via RunOnContext.
+	OutputFormat OutputFormat
+
+	StdoutHandler OutputHandler
+	StderrHandler OutputHandler
+	StatusHandler StatusHandler
+	Logger        *Logger
+
+	bastionsMu sync.Mutex
+	bastions   *bastionPool
+}
+
+// bastionPool returns the connection pool backing cfg's ProxyJump chains, creating it on first
+// use.
+func (cfg *Config) bastionPool() *bastionPool {
+	cfg.bastionsMu.Lock()
+	defer cfg.bastionsMu.Unlock()
+	if cfg.bastions == nil {
+		cfg.bastions = newBastionPool()
+	}
+	return cfg.bastions
 }
 
 func RunOn(hosts []string, cmd string, maxFlight int, cfg *Config) {
@@ -140,9 +190,16 @@ type Client struct {
 	client         *ssh.Client
 	host           string
 	sessionTimeout time.Duration
+	idleTimeout    time.Duration
 	handleStdout   OutputHandler
 	handleStderr   OutputHandler
 	handleStatus   StatusHandler
+
+	// bastions and bastionKeys track the pooled ProxyJump hops (if any) this Client borrowed to
+	// reach host, so Close can release them in reverse without tearing down a bastion another
+	// in-flight Client is still using.
+	bastions    *bastionPool
+	bastionKeys []string
 }
 
 func Dial(host string, cfg *Config) (*Client, error) {
@@ -167,49 +224,114 @@ func toAddr(host string) string {
 	return host
 }
 
-func dial(host string, cfg *Config) (*Client, error) {
-	lgr := cfg.Logger
-	lgr.Debugf("connecting")
-	addr := toAddr(host)
-	conn, err := net.DialTimeout("tcp", addr, cfg.ConnectTimeout)
-	if err != nil {
-		return nil, err
+// clientConfig builds the ssh.ClientConfig shared by every hop of a connection, direct or
+// through a ProxyJump chain.
+func clientConfig(login string, cfg *Config) (*ssh.ClientConfig, error) {
+	hostKeyCallback := cfg.HostKeyCallback
+	if hostKeyCallback == nil {
+		var err error
+		hostKeyCallback, err = PolicyCallback(StrictHostKeyChecking, DefaultKnownHostsPath())
+		if err != nil {
+			return nil, err
+		}
 	}
-	lgr.Debug("connected, setting up ssh conn")
-	clientConfig := &ssh.ClientConfig{
-		User:            cfg.Login,
-		Auth:            cfg.AuthMethods,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	return &ssh.ClientConfig{
+		Config: ssh.Config{
+			KeyExchanges: cfg.KeyExchanges,
+			Ciphers:      cfg.Ciphers,
+			MACs:         cfg.MACs,
+		},
+		User:              login,
+		Auth:              cfg.AuthMethods,
+		HostKeyCallback:   hostKeyCallback,
+		HostKeyAlgorithms: cfg.HostKeyAlgorithms,
 		// this doesn't work if the remote does not respond after a tcp conn is established.
 		Timeout: cfg.ConnectTimeout,
+	}, nil
+}
+
+// dialHop opens one ssh hop to addr and returns its *ssh.Client. When prev is nil the hop is
+// dialed directly over tcp; otherwise it is dialed through prev's connection, which is how
+// ProxyJump chains are built one bastion at a time.
+func dialHop(prev *ssh.Client, login, addr string, cfg *Config) (*ssh.Client, error) {
+	var (
+		conn net.Conn
+		err  error
+	)
+	if prev == nil {
+		conn, err = net.DialTimeout("tcp", addr, cfg.ConnectTimeout)
+	} else {
+		conn, err = prev.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cc, err := clientConfig(login, cfg)
+	if err != nil {
+		conn.Close()
+		return nil, err
 	}
-	lgr.Debugf("ConnectTimeout: %s", clientConfig.Timeout)
 
 	// clientConfig.Timeout does not work when the tcp connection is established but the remote
 	// ssh process does not respond after that.
-	conn.SetReadDeadline(time.Now().Add(cfg.ConnectTimeout))
-	sshConn, newChannel, newRequest, err := ssh.NewClientConn(conn, addr, clientConfig)
+	if cfg.ConnectTimeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(cfg.ConnectTimeout))
+	}
+	sshConn, newChannel, newRequest, err := ssh.NewClientConn(conn, addr, cc)
 	if err != nil {
+		conn.Close()
 		return nil, err
 	}
 	// reset
 	conn.SetReadDeadline(time.Time{})
+
+	return ssh.NewClient(sshConn, newChannel, newRequest), nil
+}
+
+func dial(host string, cfg *Config) (*Client, error) {
+	lgr := cfg.Logger
+	lgr.Debugf("connecting")
+	addr := toAddr(host)
+
+	var (
+		client      *ssh.Client
+		bastions    *bastionPool
+		bastionKeys []string
+		err         error
+	)
+	if len(cfg.ProxyJump) > 0 {
+		lgr.Debugf("connecting via proxy jump: %s", strings.Join(cfg.ProxyJump, ","))
+		client, bastions, bastionKeys, err = dialViaProxyJump(addr, cfg)
+	} else {
+		client, err = dialHop(nil, cfg.Login, addr, cfg)
+	}
+	if err != nil {
+		return nil, err
+	}
 	lgr.Debug("done")
 
 	return &Client{
 		lgr,
-		ssh.NewClient(sshConn, newChannel, newRequest),
+		client,
 		host,
 		cfg.SessionTimeout,
+		cfg.IdleTimeout,
 		cfg.StdoutHandler,
 		cfg.StderrHandler,
 		cfg.StatusHandler,
+		bastions,
+		bastionKeys,
 	}, nil
 }
 
 // close to release all sessions associated with the conn and maybe blocked
 func (cl *Client) Close() error {
-	return cl.client.Close() // cl.client can never be nil
+	err := cl.client.Close() // cl.client can never be nil
+	for i := len(cl.bastionKeys) - 1; i >= 0; i-- {
+		cl.bastions.release(cl.bastionKeys[i])
+	}
+	return err
 }
 
 type SessionTimeoutError struct{}
@@ -219,57 +341,94 @@ func (e *SessionTimeoutError) Error() string {
 }
 
 func (cl *Client) Run(cmd string) error {
-	err := cl.run(cmd)
+	res := cl.run(context.Background(), cmd)
 	if cl.handleStatus != nil {
-		cl.handleStatus(cl.host, err)
+		cl.handleStatus(cl.host, res.Err)
 	}
-	return err
+	return res.Err
 }
 
-// TODO: fix comment
-// can goroutines & session when timeout occurs and remote command has not exited. Session.Close()
-// does not shutdown the session stdout,stderr pipes immediately and goroutines that service them
-// will hang around till they close. session.Wait() will hang around till remote command exists.
-// Closing the underlying connection will close all sessions on that conn.
-func (cl *Client) run(cmd string) error {
+// RunContext behaves like Run, but binds the remote command's lifetime to ctx: canceling ctx
+// closes the session and cl's underlying connection so blocked reads unwind instead of hanging
+// (cl cannot run further commands after that), and the command's captured stdout/stderr, exit
+// code and duration come back as a *Result rather than only an error.
+func (cl *Client) RunContext(ctx context.Context, cmd string) *Result {
+	res := cl.run(ctx, cmd)
+	if cl.handleStatus != nil {
+		cl.handleStatus(cl.host, res.Err)
+	}
+	return res
+}
+
+// run executes cmd over a new session, tees its stdout/stderr through cl's handlers (if any)
+// while buffering them for Result, and waits for it to exit, time out, or ctx to be canceled,
+// whichever comes first. Session.Close() does not shut down the stdout/stderr pipes immediately,
+// so on timeout, idle timeout or cancellation run also closes cl's underlying connection, which
+// unblocks the tee goroutines even if the remote never closes its end; output already received
+// is still drained before run returns.
+func (cl *Client) run(ctx context.Context, cmd string) *Result {
+	start := time.Now()
+	res := &Result{}
+
 	sess, err := cl.client.NewSession()
 	if err != nil {
-		return err
+		res.Err = err
+		return res
 	}
 	defer sess.Close()
 	cl.logger.Debug("session opened")
 
-	var stdout, stderr io.Reader
-
-	if cl.handleStdout != nil {
-		stdout, err = sess.StdoutPipe()
-		if err != nil {
-			return err
-		}
+	stdout, err := sess.StdoutPipe()
+	if err != nil {
+		res.Err = err
+		return res
 	}
-
-	if cl.handleStderr != nil {
-		stderr, err = sess.StderrPipe()
-		if err != nil {
-			return err
-		}
+	stderr, err := sess.StderrPipe()
+	if err != nil {
+		res.Err = err
+		return res
 	}
 
-	if stdout != nil {
-		go func() {
-			cl.handleStdout(cl.host, stdout)
-		}()
+	var idle *idleMonitor
+	if cl.idleTimeout > 0 {
+		idle = newIdleMonitor(cl.idleTimeout)
+		defer idle.Stop()
 	}
 
-	if stderr != nil {
-		go func() {
-			cl.handleStderr(cl.host, stderr)
-		}()
-	}
+	var stdoutBuf, stderrBuf bytes.Buffer
+	var pipes sync.WaitGroup
+	pipes.Add(2)
+	go func() {
+		defer pipes.Done()
+		var out io.Reader = stdout
+		if idle != nil {
+			out = idle.reader(out)
+		}
+		r := io.TeeReader(out, &stdoutBuf)
+		if cl.handleStdout != nil {
+			cl.handleStdout(cl.host, r)
+		} else {
+			io.Copy(ioutil.Discard, r)
+		}
+	}()
+	go func() {
+		defer pipes.Done()
+		var errR io.Reader = stderr
+		if idle != nil {
+			errR = idle.reader(errR)
+		}
+		r := io.TeeReader(errR, &stderrBuf)
+		if cl.handleStderr != nil {
+			cl.handleStderr(cl.host, r)
+		} else {
+			io.Copy(ioutil.Discard, r)
+		}
+	}()
 
 	cl.logger.Debug("command started")
 	if err := sess.Start(cmd); err != nil {
-		return err
+		res.Err = err
+		return res
 	}
 
 	var d time.Duration = cl.sessionTimeout
@@ -283,19 +442,44 @@ func (cl *Client) run(cmd string) error {
 		status <- sess.Wait()
 	}()
 
-	var exit error
+	var idleC <-chan struct{}
+	if idle != nil {
+		idleC = idle.idleC
+	}
 
-	// block until timeout or we get status
+	// block until timeout, idle timeout, ctx is canceled, or we get status
 	select {
+	case <-ctx.Done():
+		cl.logger.Debug("context canceled")
+		sess.Signal(ssh.SIGQUIT) // attempt to signal the other end
+		sess.Close()
+		cl.client.Close()
+		timeout.Stop()
+		res.Err = ctx.Err()
 	case <-timeout.C:
 		cl.logger.Debug("command timed out")
 		sess.Signal(ssh.SIGQUIT) // attempt to signal the other end
 		sess.Close()
-		exit = &SessionTimeoutError{}
-	case exit = <-status:
+		cl.client.Close()
+		res.Err = &SessionTimeoutError{}
+	case <-idleC:
+		cl.logger.Debug("session idle")
+		sess.Signal(ssh.SIGQUIT) // attempt to signal the other end
+		sess.Close()
+		cl.client.Close()
+		timeout.Stop()
+		res.Err = &SessionIdleError{}
+	case res.Err = <-status:
 		cl.logger.Debug("command exited")
 		timeout.Stop()
 	}
 
-	return exit
+	pipes.Wait()
+	res.Duration = time.Since(start)
+	res.Stdout = stdoutBuf.Bytes()
+	res.Stderr = stderrBuf.Bytes()
+	if exitErr, ok := res.Err.(*ssh.ExitError); ok {
+		res.ExitCode = exitErr.ExitStatus()
+	}
+	return res
 }
@@ -0,0 +1,125 @@
+package gossh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// HostKeyPolicy selects how gossh verifies server host keys, mirroring OpenSSH's
+// StrictHostKeyChecking ssh_config option.
+type HostKeyPolicy int
+
+const (
+	// StrictHostKeyChecking refuses host keys that are unknown or that don't match known_hosts.
+	StrictHostKeyChecking HostKeyPolicy = iota
+	// AcceptNewHostKeys trusts a host key the first time it's seen (trust-on-first-use).
+	AcceptNewHostKeys
+	// IgnoreHostKeys disables verification entirely.
+	IgnoreHostKeys
+)
+
+// tofuMu serializes TrustOnFirstUseCallback's read-modify-append of a known_hosts file across a
+// RunOn fan-out.
+var tofuMu sync.Mutex
+
+// KnownHostsCallback returns an ssh.HostKeyCallback that verifies server host keys against the
+// given OpenSSH known_hosts files. @cert-authority and @revoked markers are honored natively by
+// golang.org/x/crypto/ssh/knownhosts. Mismatches come back as a *knownhosts.KeyError and
+// revocations as a *knownhosts.RevokedError, so callers can type-switch on either to surface them
+// via StatusHandler.
+func KnownHostsCallback(files ...string) (ssh.HostKeyCallback, error) {
+	return knownhosts.New(files...)
+}
+
+// TrustOnFirstUseCallback returns an ssh.HostKeyCallback implementing TOFU semantics against the
+// known_hosts file at path: keys already recorded there are verified normally, while a host seen
+// for the first time has its key appended to path and is accepted.
+func TrustOnFirstUseCallback(path string) (ssh.HostKeyCallback, error) {
+	if err := ensureKnownHosts(path); err != nil {
+		return nil, err
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		tofuMu.Lock()
+		defer tofuMu.Unlock()
+
+		cb, err := knownhosts.New(path)
+		if err != nil {
+			return err
+		}
+
+		err = cb(hostname, remote, key)
+		if err == nil {
+			return nil
+		}
+
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) || len(keyErr.Want) != 0 {
+			// either a different failure or a key that changed: never auto-trust that.
+			return err
+		}
+
+		return appendKnownHost(path, hostname, key)
+	}, nil
+}
+
+// PolicyCallback builds an ssh.HostKeyCallback for policy against the known_hosts file at path,
+// creating path if it does not yet exist.
+func PolicyCallback(policy HostKeyPolicy, path string) (ssh.HostKeyCallback, error) {
+	switch policy {
+	case IgnoreHostKeys:
+		return ssh.InsecureIgnoreHostKey(), nil
+	case AcceptNewHostKeys:
+		return TrustOnFirstUseCallback(path)
+	default:
+		if err := ensureKnownHosts(path); err != nil {
+			return nil, err
+		}
+		return KnownHostsCallback(path)
+	}
+}
+
+// DefaultKnownHostsPath returns ~/.ssh/known_hosts for the current user, or "" if the home
+// directory can't be determined.
+func DefaultKnownHostsPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".ssh", "known_hosts")
+}
+
+func ensureKnownHosts(path string) error {
+	if _, err := os.Stat(path); err == nil || !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func appendKnownHost(path, hostname string, key ssh.PublicKey) error {
+	// O_APPEND makes each write atomic with respect to other appenders on POSIX, which is all
+	// the locking a single known_hosts line needs.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	line := knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key)
+	_, err = fmt.Fprintln(f, line)
+	return err
+}
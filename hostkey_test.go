@@ -0,0 +1,65 @@
+package gossh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func genHostKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestTrustOnFirstUseCallback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	cb, err := TrustOnFirstUseCallback(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 22}
+	key := genHostKey(t)
+
+	if err := cb("example.com:22", addr, key); err != nil {
+		t.Fatalf("first sighting: got err %v, want nil (auto-trust)", err)
+	}
+	if err := cb("example.com:22", addr, key); err != nil {
+		t.Fatalf("known key: got err %v, want nil", err)
+	}
+
+	err = cb("example.com:22", addr, genHostKey(t))
+	if _, ok := err.(*knownhosts.KeyError); !ok {
+		t.Fatalf("changed key: got %T (%v), want *knownhosts.KeyError", err, err)
+	}
+}
+
+func TestKnownHostsCallbackUnknownHost(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	if err := ensureKnownHosts(path); err != nil {
+		t.Fatal(err)
+	}
+	cb, err := KnownHostsCallback(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 22}
+	err = cb("example.com:22", addr, genHostKey(t))
+	if _, ok := err.(*knownhosts.KeyError); !ok {
+		t.Fatalf("got %T (%v), want *knownhosts.KeyError", err, err)
+	}
+}
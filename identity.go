@@ -0,0 +1,85 @@
+package gossh
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// signerCache caches decrypted signers per identity file so a RunOn fan-out decrypts (and prompts
+// for) each key only once.
+var (
+	signerCacheMu sync.Mutex
+	signerCache   = make(map[string]ssh.Signer)
+)
+
+func cachedSigner(file string) (ssh.Signer, bool) {
+	signerCacheMu.Lock()
+	defer signerCacheMu.Unlock()
+	signer, ok := signerCache[file]
+	return signer, ok
+}
+
+func cacheSigner(file string, signer ssh.Signer) {
+	signerCacheMu.Lock()
+	defer signerCacheMu.Unlock()
+	signerCache[file] = signer
+}
+
+// IdentityFileWithPassphrase converts a passphrase-protected ssh key file to an ssh.AuthMethod,
+// handling both passphrase-encrypted PEM and the OpenSSH v1 encrypted key format.
+func IdentityFileWithPassphrase(file string, passphrase []byte) (ssh.AuthMethod, error) {
+	if signer, ok := cachedSigner(file); ok {
+		return ssh.PublicKeys(signer), nil
+	}
+
+	pem, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("IdentityFileWithPassphrase: %s", err)
+	}
+
+	signer, err := ssh.ParsePrivateKeyWithPassphrase(pem, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("IdentityFileWithPassphrase: %s", err)
+	}
+
+	cacheSigner(file, signer)
+	return ssh.PublicKeys(signer), nil
+}
+
+// IdentityFilePrompt converts an ssh key file to an ssh.AuthMethod, calling prompt for a
+// passphrase only if the key at file turns out to be encrypted. prompt is handed the key's
+// fingerprint so a caller prompting interactively can say which key it's unlocking, or "" when
+// the encrypted file does not carry its public key (as with legacy encrypted PEM).
+func IdentityFilePrompt(file string, prompt func(fingerprint string) ([]byte, error)) (ssh.AuthMethod, error) {
+	if signer, ok := cachedSigner(file); ok {
+		return ssh.PublicKeys(signer), nil
+	}
+
+	pem, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("IdentityFilePrompt: %s", err)
+	}
+
+	signer, err := ssh.ParsePrivateKey(pem)
+	if missingErr, missing := err.(*ssh.PassphraseMissingError); missing {
+		fingerprint := ""
+		if missingErr.PublicKey != nil {
+			fingerprint = ssh.FingerprintSHA256(missingErr.PublicKey)
+		}
+
+		passphrase, perr := prompt(fingerprint)
+		if perr != nil {
+			return nil, fmt.Errorf("IdentityFilePrompt: %s", perr)
+		}
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(pem, passphrase)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("IdentityFilePrompt: %s", err)
+	}
+
+	cacheSigner(file, signer)
+	return ssh.PublicKeys(signer), nil
+}
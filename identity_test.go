@@ -0,0 +1,121 @@
+package gossh
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// genEncryptedRSAKey returns a PEM-encoded RSA private key encrypted with passphrase, in the
+// legacy OpenSSL format that ssh.ParsePrivateKeyWithPassphrase understands.
+func genEncryptedRSAKey(t *testing.T, passphrase []byte) []byte {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY",
+		x509.MarshalPKCS1PrivateKey(key), passphrase, x509.PEMCipherAES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(block)
+}
+
+func writeIdentityFile(t *testing.T, data []byte) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "id_rsa")
+	if err := os.WriteFile(file, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestSignerCache(t *testing.T) {
+	if _, ok := cachedSigner("nope"); ok {
+		t.Fatal("cachedSigner found an entry for a file never cached")
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+	signer, err := ssh.ParsePrivateKey(pem.EncodeToMemory(block))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cacheSigner("k", signer)
+	got, ok := cachedSigner("k")
+	if !ok {
+		t.Fatal("cachedSigner did not find an entry just cached")
+	}
+	if got != signer {
+		t.Fatal("cachedSigner returned a different signer than the one cached")
+	}
+}
+
+func TestIdentityFileWithPassphraseCaches(t *testing.T) {
+	passphrase := []byte("hunter2")
+	file := writeIdentityFile(t, genEncryptedRSAKey(t, passphrase))
+
+	if _, err := IdentityFileWithPassphrase(file, passphrase); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cachedSigner(file); !ok {
+		t.Fatal("IdentityFileWithPassphrase did not cache the decrypted signer")
+	}
+
+	if _, err := IdentityFileWithPassphrase(file, []byte("wrong passphrase")); err != nil {
+		t.Fatalf("second call should have hit the cache and skipped decryption, got err %v", err)
+	}
+}
+
+func TestIdentityFilePromptFallsBackOnMissingPassphrase(t *testing.T) {
+	passphrase := []byte("hunter2")
+	file := writeIdentityFile(t, genEncryptedRSAKey(t, passphrase))
+
+	prompted := false
+	prompt := func(fingerprint string) ([]byte, error) {
+		prompted = true
+		return passphrase, nil
+	}
+
+	if _, err := IdentityFilePrompt(file, prompt); err != nil {
+		t.Fatal(err)
+	}
+	if !prompted {
+		t.Fatal("IdentityFilePrompt did not call prompt for an encrypted key")
+	}
+	if _, ok := cachedSigner(file); !ok {
+		t.Fatal("IdentityFilePrompt did not cache the decrypted signer")
+	}
+
+	if _, err := IdentityFilePrompt(file, func(string) ([]byte, error) {
+		t.Fatal("prompt should not be called once the signer is cached")
+		return nil, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIdentityFilePromptPropagatesPromptError(t *testing.T) {
+	passphrase := []byte("hunter2")
+	file := writeIdentityFile(t, genEncryptedRSAKey(t, passphrase))
+
+	_, err := IdentityFilePrompt(file, func(string) ([]byte, error) {
+		return nil, errors.New("prompt declined")
+	})
+	if err == nil || !strings.Contains(err.Error(), "prompt declined") {
+		t.Fatalf("got err %v, want one wrapping %q", err, "prompt declined")
+	}
+}
@@ -0,0 +1,81 @@
+package gossh
+
+import (
+	"io"
+	"time"
+)
+
+// SessionIdleError is returned when a session's IdleTimeout elapses with no stdout/stderr bytes
+// seen, distinct from the wall-clock SessionTimeoutError.
+type SessionIdleError struct{}
+
+func (e *SessionIdleError) Error() string {
+	return "session idle timeout"
+}
+
+// idleMonitor watches Read activity reported by one or more idleReader-wrapped readers and closes
+// idleC once idle passes with no activity on any of them. It owns the single underlying timer
+// itself, so unlike handing that timer to each reader directly, nothing else ever races Stop,
+// Reset or a receive against it.
+type idleMonitor struct {
+	idle     time.Duration
+	activity chan struct{}
+	idleC    chan struct{}
+	stop     chan struct{}
+}
+
+func newIdleMonitor(idle time.Duration) *idleMonitor {
+	m := &idleMonitor{
+		idle:     idle,
+		activity: make(chan struct{}, 1),
+		idleC:    make(chan struct{}),
+		stop:     make(chan struct{}),
+	}
+	go m.run()
+	return m
+}
+
+func (m *idleMonitor) run() {
+	timer := time.NewTimer(m.idle)
+	defer timer.Stop()
+	for {
+		select {
+		case <-m.activity:
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(m.idle)
+		case <-timer.C:
+			close(m.idleC)
+			return
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// Stop tells run to exit without closing idleC, for when the session ended some other way first.
+func (m *idleMonitor) Stop() {
+	close(m.stop)
+}
+
+// reader wraps r so every non-empty Read pings m's activity channel.
+func (m *idleMonitor) reader(r io.Reader) io.Reader {
+	return &idleActivityReader{r, m}
+}
+
+type idleActivityReader struct {
+	r io.Reader
+	m *idleMonitor
+}
+
+func (ar *idleActivityReader) Read(p []byte) (int, error) {
+	n, err := ar.r.Read(p)
+	if n > 0 {
+		select {
+		case ar.m.activity <- struct{}{}:
+		default:
+		}
+	}
+	return n, err
+}
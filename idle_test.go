@@ -0,0 +1,42 @@
+package gossh
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdleMonitorFiresWhenIdle(t *testing.T) {
+	m := newIdleMonitor(20 * time.Millisecond)
+	defer m.Stop()
+
+	select {
+	case <-m.idleC:
+	case <-time.After(time.Second):
+		t.Fatal("idleC did not fire within 1s of no activity")
+	}
+}
+
+func TestIdleMonitorResetsOnActivity(t *testing.T) {
+	m := newIdleMonitor(50 * time.Millisecond)
+	defer m.Stop()
+
+	r := m.reader(strings.NewReader("hello"))
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-m.idleC:
+		t.Fatal("idleC fired immediately after a Read, before idle elapsed")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	select {
+	case <-m.idleC:
+	case <-time.After(time.Second):
+		t.Fatal("idleC did not fire once activity stopped")
+	}
+}
@@ -0,0 +1,182 @@
+package gossh
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Result is the outcome of a single command run via RunContext/Client.RunContext.
+type Result struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+	Err      error
+	Duration time.Duration
+}
+
+// HostResult is a Result tagged with the host it came from, as delivered by RunOnContext.
+type HostResult struct {
+	Host     string
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+	Err      error
+	Duration time.Duration
+}
+
+// RunContext dials host and runs cmd, same as Run, but bound to ctx.
+func RunContext(ctx context.Context, host, cmd string, cfg *Config) (*Result, error) {
+	client, err := Dial(host, cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	return client.RunContext(ctx, cmd), nil
+}
+
+// RunOnContext fans cmd out to hosts like RunOn, but also delivers one HostResult per host on the
+// returned channel; cfg's handlers, if set, are still called as with RunOn. Once ctx is canceled,
+// hosts not yet started and results not yet received are dropped.
+func RunOnContext(ctx context.Context, hosts []string, cmd string, maxFlight int, cfg *Config) <-chan HostResult {
+	if maxFlight < 1 {
+		maxFlight = 1
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = GetLogger("main", 2)
+	}
+
+	results := make(chan HostResult)
+	go func() {
+		workers := new(sync.WaitGroup)
+		defer close(results)
+		defer workers.Wait()
+
+		sem := make(chan struct{}, maxFlight)
+		for _, host := range hosts {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			workers.Add(1)
+			go func(host string) {
+				defer func() {
+					workers.Done()
+					<-sem
+				}()
+
+				hr := HostResult{Host: host}
+				res, err := RunContext(ctx, host, cmd, cfg)
+				if err != nil {
+					hr.Err = err
+				} else {
+					hr.Stdout, hr.Stderr, hr.ExitCode, hr.Err, hr.Duration =
+						res.Stdout, res.Stderr, res.ExitCode, res.Err, res.Duration
+				}
+
+				select {
+				case results <- hr:
+				case <-ctx.Done():
+				}
+			}(host)
+		}
+	}()
+	return results
+}
+
+// OutputFormat selects how a command's output is rendered downstream.
+type OutputFormat int
+
+const (
+	OutputText OutputFormat = iota
+	OutputJSON
+	OutputNDJSONStream
+)
+
+func (f OutputFormat) String() string {
+	switch f {
+	case OutputJSON:
+		return "json"
+	case OutputNDJSONStream:
+		return "ndjson-stream"
+	default:
+		return "text"
+	}
+}
+
+// ParseOutputFormat parses the -o OutputFormat= CLI value into an OutputFormat.
+func ParseOutputFormat(s string) (OutputFormat, error) {
+	switch s {
+	case "", "text":
+		return OutputText, nil
+	case "json":
+		return OutputJSON, nil
+	case "ndjson-stream":
+		return OutputNDJSONStream, nil
+	default:
+		return OutputText, fmt.Errorf("unknown output format %q, want text, json or ndjson-stream", s)
+	}
+}
+
+// PrintResultJSON writes hr to stdout as a single line of JSON.
+func PrintResultJSON(hr HostResult) {
+	errMsg := ""
+	if hr.Err != nil {
+		errMsg = hr.Err.Error()
+	}
+	b, err := json.Marshal(struct {
+		Host     string `json:"host"`
+		Stdout   string `json:"stdout"`
+		Stderr   string `json:"stderr"`
+		ExitCode int    `json:"exit_code"`
+		Err      string `json:"err,omitempty"`
+		Duration string `json:"duration"`
+	}{hr.Host, string(hr.Stdout), string(hr.Stderr), hr.ExitCode, errMsg, hr.Duration.String()})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gossh.PrintResultJSON(%s): %s\n", hr.Host, err)
+		return
+	}
+
+	stdoutMutex.Lock()
+	defer stdoutMutex.Unlock()
+	fmt.Println(string(b))
+}
+
+type ndjsonChunk struct {
+	Host   string `json:"host"`
+	Stream string `json:"stream"`
+	Line   string `json:"line"`
+}
+
+func printNDJSON(host, stream string, r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		b, err := json.Marshal(ndjsonChunk{host, stream, scanner.Text()})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gossh.printNDJSON(%s, %s): %s\n", host, stream, err)
+			continue
+		}
+		stdoutMutex.Lock()
+		fmt.Println(string(b))
+		stdoutMutex.Unlock()
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "gossh.printNDJSON(%s, %s): %s\n", host, stream, err)
+	}
+}
+
+// NDJSONStdout is an OutputHandler for the OutputNDJSONStream format.
+func NDJSONStdout(host string, stream io.Reader) {
+	printNDJSON(host, "stdout", stream)
+}
+
+func NDJSONStderr(host string, stream io.Reader) {
+	printNDJSON(host, "stderr", stream)
+}
@@ -0,0 +1,53 @@
+package gossh
+
+import (
+	"errors"
+	"testing"
+)
+
+var parseOutputFormatSets = []struct {
+	inp string
+	res OutputFormat
+	err bool
+}{
+	{"", OutputText, false},
+	{"text", OutputText, false},
+	{"json", OutputJSON, false},
+	{"ndjson-stream", OutputNDJSONStream, false},
+	{"bogus", OutputText, true},
+}
+
+func TestParseOutputFormat(t *testing.T) {
+	for _, e := range parseOutputFormatSets {
+		res, err := ParseOutputFormat(e.inp)
+		if (err != nil) != e.err {
+			t.Errorf("ParseOutputFormat(%q) error = %v, want err=%v", e.inp, err, e.err)
+			continue
+		}
+		if res != e.res {
+			t.Errorf("ParseOutputFormat(%q) = %v, want %v", e.inp, res, e.res)
+		}
+	}
+}
+
+var outputFormatStringSets = []struct {
+	f   OutputFormat
+	res string
+}{
+	{OutputText, "text"},
+	{OutputJSON, "json"},
+	{OutputNDJSONStream, "ndjson-stream"},
+}
+
+func TestOutputFormatString(t *testing.T) {
+	for _, e := range outputFormatStringSets {
+		if got := e.f.String(); got != e.res {
+			t.Errorf("%v.String() = %q, want %q", int(e.f), got, e.res)
+		}
+	}
+}
+
+func TestPrintResultJSONNoPanic(t *testing.T) {
+	PrintResultJSON(HostResult{Host: "h1", Err: errors.New("boom")})
+	PrintResultJSON(HostResult{Host: "h2"})
+}
@@ -0,0 +1,117 @@
+package gossh
+
+import (
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// jumpHop is one parsed leg of a ProxyJump chain, e.g. "user@bastion1:22".
+type jumpHop struct {
+	login string
+	addr  string
+}
+
+func parseProxyJump(proxyJump []string, defaultLogin string) []jumpHop {
+	hops := make([]jumpHop, len(proxyJump))
+	for i, h := range proxyJump {
+		login, addr := defaultLogin, h
+		if idx := strings.LastIndex(h, "@"); idx != -1 {
+			login, addr = h[:idx], h[idx+1:]
+		}
+		hops[i] = jumpHop{login, toAddr(addr)}
+	}
+	return hops
+}
+
+// bastionPool caches open *ssh.Client connections to bastion hosts, keyed by the chain prefix
+// leading to them.
+type bastionPool struct {
+	mu    sync.Mutex
+	conns map[string]*pooledBastion
+}
+
+type pooledBastion struct {
+	client   *ssh.Client
+	refCount int
+}
+
+func newBastionPool() *bastionPool {
+	return &bastionPool{conns: make(map[string]*pooledBastion)}
+}
+
+// acquire returns the *ssh.Client cached for key, calling open to dial one if there isn't one yet.
+// Every successful acquire must be matched with a release(key).
+func (p *bastionPool) acquire(key string, open func() (*ssh.Client, error)) (*ssh.Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if b, ok := p.conns[key]; ok {
+		b.refCount++
+		return b.client, nil
+	}
+
+	client, err := open()
+	if err != nil {
+		return nil, err
+	}
+	p.conns[key] = &pooledBastion{client: client, refCount: 1}
+	return client, nil
+}
+
+// release drops one reference to the bastion cached under key, closing and evicting it once
+// nothing else is using it.
+func (p *bastionPool) release(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	b, ok := p.conns[key]
+	if !ok {
+		return
+	}
+	b.refCount--
+	if b.refCount <= 0 {
+		b.client.Close()
+		delete(p.conns, key)
+	}
+}
+
+// dialViaProxyJump dials addr through the bastion chain in cfg.ProxyJump, reusing pooled
+// connections for any chain prefix already open. On success it returns the final *ssh.Client for
+// addr, the pool, and the keys (outermost hop first) the caller must release, in reverse, when
+// it closes the connection.
+func dialViaProxyJump(addr string, cfg *Config) (client *ssh.Client, pool *bastionPool, keys []string, err error) {
+	hops := parseProxyJump(cfg.ProxyJump, cfg.Login)
+	pool = cfg.bastionPool()
+
+	defer func() {
+		if err != nil {
+			for i := len(keys) - 1; i >= 0; i-- {
+				pool.release(keys[i])
+			}
+			keys = nil
+		}
+	}()
+
+	var current *ssh.Client
+	var prefix string
+	for _, h := range hops {
+		prefix += h.login + "@" + h.addr + ";"
+		key, hop, prev := prefix, h, current
+
+		current, err = pool.acquire(key, func() (*ssh.Client, error) {
+			return dialHop(prev, hop.login, hop.addr, cfg)
+		})
+		if err != nil {
+			return nil, pool, keys, err
+		}
+		keys = append(keys, key)
+	}
+
+	client, err = dialHop(current, cfg.Login, addr, cfg)
+	if err != nil {
+		return nil, pool, keys, err
+	}
+	return client, pool, keys, nil
+}
@@ -0,0 +1,127 @@
+package gossh
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// fakeConn is a minimal ssh.Conn that lets tests construct a real *ssh.Client whose Close() is
+// observable, without dialing anything.
+type fakeConn struct {
+	closed chan struct{}
+}
+
+func newFakeClient() (*ssh.Client, <-chan struct{}) {
+	fc := &fakeConn{closed: make(chan struct{})}
+	client := ssh.NewClient(fc, make(chan ssh.NewChannel), make(chan *ssh.Request))
+	return client, fc.closed
+}
+
+func (c *fakeConn) User() string          { return "" }
+func (c *fakeConn) SessionID() []byte     { return nil }
+func (c *fakeConn) ClientVersion() []byte { return nil }
+func (c *fakeConn) ServerVersion() []byte { return nil }
+func (c *fakeConn) RemoteAddr() net.Addr  { return nil }
+func (c *fakeConn) LocalAddr() net.Addr   { return nil }
+func (c *fakeConn) Wait() error           { return nil }
+func (c *fakeConn) SendRequest(string, bool, []byte) (bool, []byte, error) {
+	return false, nil, nil
+}
+func (c *fakeConn) OpenChannel(string, []byte) (ssh.Channel, <-chan *ssh.Request, error) {
+	return nil, nil, errors.New("fakeConn: OpenChannel not supported")
+}
+func (c *fakeConn) Close() error {
+	close(c.closed)
+	return nil
+}
+
+var parseProxyJumpSets = []struct {
+	inp          []string
+	defaultLogin string
+	res          []jumpHop
+}{
+	{
+		[]string{"bastion1"},
+		"alice",
+		[]jumpHop{{"alice", "bastion1:22"}},
+	},
+	{
+		[]string{"bob@bastion1:2222", "bastion2"},
+		"alice",
+		[]jumpHop{{"bob", "bastion1:2222"}, {"alice", "bastion2:22"}},
+	},
+}
+
+func TestParseProxyJump(t *testing.T) {
+	for _, e := range parseProxyJumpSets {
+		res := parseProxyJump(e.inp, e.defaultLogin)
+		if !reflect.DeepEqual(res, e.res) {
+			t.Errorf("parseProxyJump(%v, %q) = %v, want %v", e.inp, e.defaultLogin, res, e.res)
+		}
+	}
+}
+
+func TestBastionPoolAcquireRelease(t *testing.T) {
+	p := newBastionPool()
+
+	client, closed := newFakeClient()
+	opens := 0
+	open := func() (*ssh.Client, error) {
+		opens++
+		return client, nil
+	}
+
+	c1, err := p.acquire("k", open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := p.acquire("k", open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c1 != c2 {
+		t.Fatal("acquire returned different clients for the same key")
+	}
+	if opens != 1 {
+		t.Fatalf("open called %d times, want 1 (second acquire should reuse the cached conn)", opens)
+	}
+
+	p.release("k")
+	select {
+	case <-closed:
+		t.Fatal("release closed the pooled conn while a reference was still outstanding")
+	default:
+	}
+	if _, ok := p.conns["k"]; !ok {
+		t.Fatal("release evicted the pooled conn while a reference was still outstanding")
+	}
+
+	p.release("k")
+	select {
+	case <-closed:
+	default:
+		t.Fatal("release did not close the pooled conn once its last reference was dropped")
+	}
+	if _, ok := p.conns["k"]; ok {
+		t.Fatal("release did not evict the pooled conn once its last reference was dropped")
+	}
+}
+
+func TestBastionPoolAcquireOpenError(t *testing.T) {
+	p := newBastionPool()
+	wantErr := errors.New("dial failed")
+
+	_, err := p.acquire("k", func() (*ssh.Client, error) {
+		return nil, wantErr
+	})
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("acquire() error = %v, want %v", err, wantErr)
+	}
+	if _, ok := p.conns["k"]; ok {
+		t.Fatal("acquire cached a conn despite open failing")
+	}
+}
@@ -0,0 +1,89 @@
+package gossh
+
+import (
+	"io"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// PTYOptions configures the pseudo-terminal requested by Client.RunPTY.
+type PTYOptions struct {
+	// Term is the $TERM value to request; "xterm" if empty.
+	Term string
+	// Width and Height are the initial terminal size in columns and rows; 80x24 if zero.
+	Width, Height int
+	Modes         ssh.TerminalModes
+
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// WindowChange, if non-nil, is read for [width, height] pairs for the life of the session and
+	// forwarded to the remote pty, e.g. to relay a local SIGWINCH.
+	WindowChange <-chan [2]int
+}
+
+// RunPTY runs cmd on an interactively allocated pty, or the login shell if cmd is empty, wiring
+// opts.Stdin/Stdout/Stderr to it and forwarding any size changes delivered on
+// opts.WindowChange. It blocks until the remote command or shell exits.
+func (cl *Client) RunPTY(cmd string, opts PTYOptions) error {
+	sess, err := cl.client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer sess.Close()
+
+	term, width, height := ptyDefaults(opts)
+
+	if err := sess.RequestPty(term, height, width, opts.Modes); err != nil {
+		return err
+	}
+
+	sess.Stdin = opts.Stdin
+	sess.Stdout = opts.Stdout
+	sess.Stderr = opts.Stderr
+
+	if opts.WindowChange != nil {
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			for {
+				select {
+				case wh, ok := <-opts.WindowChange:
+					if !ok {
+						return
+					}
+					sess.WindowChange(wh[1], wh[0])
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	if cmd == "" {
+		if err := sess.Shell(); err != nil {
+			return err
+		}
+	} else if err := sess.Start(cmd); err != nil {
+		return err
+	}
+
+	return sess.Wait()
+}
+
+// ptyDefaults fills in opts' Term/Width/Height with RunPTY's defaults where left zero-valued.
+func ptyDefaults(opts PTYOptions) (term string, width, height int) {
+	term = opts.Term
+	if term == "" {
+		term = "xterm"
+	}
+	width, height = opts.Width, opts.Height
+	if width == 0 {
+		width = 80
+	}
+	if height == 0 {
+		height = 24
+	}
+	return term, width, height
+}
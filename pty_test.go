@@ -0,0 +1,24 @@
+package gossh
+
+import "testing"
+
+var ptyDefaultsSets = []struct {
+	opts          PTYOptions
+	term          string
+	width, height int
+}{
+	{PTYOptions{}, "xterm", 80, 24},
+	{PTYOptions{Term: "vt100", Width: 132, Height: 43}, "vt100", 132, 43},
+	{PTYOptions{Width: 100}, "xterm", 100, 24},
+	{PTYOptions{Height: 50}, "xterm", 80, 50},
+}
+
+func TestPTYDefaults(t *testing.T) {
+	for _, e := range ptyDefaultsSets {
+		term, width, height := ptyDefaults(e.opts)
+		if term != e.term || width != e.width || height != e.height {
+			t.Errorf("ptyDefaults(%+v) = (%q, %d, %d), want (%q, %d, %d)",
+				e.opts, term, width, height, e.term, e.width, e.height)
+		}
+	}
+}
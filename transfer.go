@@ -0,0 +1,121 @@
+package gossh
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+
+	"github.com/pkg/sftp"
+)
+
+// Transfer moves files to and from a remote host over the sftp subsystem of an already
+// established SSH connection.
+type Transfer struct {
+	client *sftp.Client
+}
+
+// Transfer opens the sftp subsystem on cl's connection. The caller must Close the returned
+// Transfer when done; doing so does not close cl.
+func (cl *Client) Transfer() (*Transfer, error) {
+	client, err := sftp.NewClient(cl.client)
+	if err != nil {
+		return nil, err
+	}
+	return &Transfer{client}, nil
+}
+
+// Close closes the sftp subsystem, but not the underlying SSH connection.
+func (t *Transfer) Close() error {
+	return t.client.Close()
+}
+
+// Put copies local to remote on the server, creating or truncating remote with the given mode.
+func (t *Transfer) Put(local, remote string, mode os.FileMode) error {
+	src, err := os.Open(local)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := t.client.OpenFile(remote, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return err
+	}
+	return dst.Chmod(mode)
+}
+
+// Get copies remote from the server to local, preserving remote's mode.
+func (t *Transfer) Get(remote, local string) error {
+	src, err := t.client.Open(remote)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	dst, err := os.OpenFile(local, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode())
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// PutFS uploads every regular file in fsys to remoteRoot on the server, recreating fsys's
+// directory structure under remoteRoot and preserving each file's mode.
+func (t *Transfer) PutFS(fsys fs.FS, remoteRoot string) error {
+	return fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		remotePath := putFSRemotePath(remoteRoot, p)
+
+		if d.IsDir() {
+			return t.client.MkdirAll(remotePath)
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		src, err := fsys.Open(p)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		dst, err := t.client.OpenFile(remotePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+		if err != nil {
+			return err
+		}
+		defer dst.Close()
+
+		if _, err := io.Copy(dst, src); err != nil {
+			return err
+		}
+		return dst.Chmod(info.Mode())
+	})
+}
+
+// putFSRemotePath maps a path p from fs.WalkDir (which reports the root as ".") onto its
+// destination under remoteRoot.
+func putFSRemotePath(remoteRoot, p string) string {
+	if p == "." {
+		return remoteRoot
+	}
+	return path.Join(remoteRoot, p)
+}
@@ -0,0 +1,20 @@
+package gossh
+
+import "testing"
+
+var putFSRemotePathSets = []struct {
+	remoteRoot, p string
+	res           string
+}{
+	{"/srv/app", ".", "/srv/app"},
+	{"/srv/app", "config.yml", "/srv/app/config.yml"},
+	{"/srv/app", "sub/dir/file.txt", "/srv/app/sub/dir/file.txt"},
+}
+
+func TestPutFSRemotePath(t *testing.T) {
+	for _, e := range putFSRemotePathSets {
+		if got := putFSRemotePath(e.remoteRoot, e.p); got != e.res {
+			t.Errorf("putFSRemotePath(%q, %q) = %q, want %q", e.remoteRoot, e.p, got, e.res)
+		}
+	}
+}